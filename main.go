@@ -2,53 +2,148 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"flag"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/spf13/pflag"
+	"golang.org/x/time/rate"
 )
 
-// ResolverStatus holds the Alive boolean flag and the Name of the DNS resolver.
+// ResolverStatus holds the Alive boolean flag, the Name of the DNS resolver,
+// the Protocol the check succeeded over, and, in benchmark mode, latency
+// and loss statistics gathered across the configured number of trials.
 type ResolverStatus struct {
-	Alive bool
-	Name  string
+	Alive    bool
+	Name     string
+	Protocol string
+	MinMs    float64
+	MedianMs float64
+	P95Ms    float64
+	MaxMs    float64
+	LossPct  float64
+	Hijacked bool
+
+	Capabilities Capabilities
 }
 
-// ResolverCheck holds the DNS resolver name, network protocol, and timeout duration.
+// Capabilities holds the results of optional, --capabilities-gated probing
+// of DNSSEC validation and EDNS0/NSID support.
+type Capabilities struct {
+	DNSSECValidates bool
+	SupportsEDNS0   bool
+	NSID            string
+	MaxUDPSize      uint16
+}
+
+// ResolverCheck holds the DNS resolver name, network protocol, optional
+// DoH path, and timeout duration.
 type ResolverCheck struct {
 	Resolver string
 	Protocol string
+	Path     string
 	Timeout  time.Duration
 }
 
+// Config holds the fully-resolved settings for a run, after merging
+// command-line flags over an optional INI config file.
+type Config struct {
+	List            string
+	Output          string
+	Format          string
+	Protocol        string
+	Workers         int
+	Timeout         time.Duration
+	Benchmark       bool
+	Trials          int
+	Baseline        string
+	HijackHosts     []string
+	ExcludeHijacked bool
+	Capabilities    bool
+	Rate            float64
+	Retries         int
+	Verbosity       int
+	Silent          bool
+}
+
+// runner bundles the retry budget and silent flag shared by every worker
+// goroutine.
+type runner struct {
+	retries int
+	silent  bool
+}
+
 // List of test hosts.
 var testHosts = []string{"google.com", "cloudflare.com", "amazon.com"}
 
+// rateLimiter throttles every real DNS query issued via sendQuery, so
+// --rate bounds the actual query rate rather than just the rate at which
+// workers pick up new resolvers. nil means unlimited. It's set once in
+// main() before any worker goroutine starts, so it's safe to read
+// without further synchronization.
+var rateLimiter *rate.Limiter
+
 func main() {
-	// Parsing command-line flags.
-	list, output, protocol, workers, timeoutSec, silent := ParseFlags()
+	// Parsing command-line flags (and, if present, an INI config file).
+	cfg := ParseFlags()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel(cfg)})))
 
 	// Loading DNS resolvers from a provided list.
-	resolverChecks, err := LoadResolvers(list, protocol, time.Duration(timeoutSec)*time.Second)
+	resolverChecks, err := LoadResolvers(cfg.List, cfg.Protocol, cfg.Timeout)
 	if err != nil {
-		fmt.Println(err)
+		slog.Error(err.Error())
 		os.Exit(1)
 	}
 
+	rt := &runner{retries: cfg.Retries, silent: cfg.Silent}
+	if cfg.Rate > 0 {
+		rateLimiter = rate.NewLimiter(rate.Limit(cfg.Rate), 1)
+	}
+
 	// Performing DNS checks.
-	statuses := CheckResolvers(resolverChecks, workers, silent)
+	statuses := CheckResolvers(resolverChecks, cfg.Workers, cfg.Benchmark, cfg.Trials, cfg.Baseline, cfg.HijackHosts, cfg.ExcludeHijacked, cfg.Capabilities, rt)
 
 	// If an output file is provided, writing DNS resolver statuses to the output file.
-	if output != "" {
-		writeFile(output, statuses)
+	if cfg.Output != "" {
+		writeFile(cfg.Output, cfg.Format, statuses)
 	}
 }
 
-// CheckResolvers performs DNS checks with requested workers.
-func CheckResolvers(resolverChecks []ResolverCheck, workers int, silent bool) []ResolverStatus {
+// logLevel maps --silent/-v/-vv to a slog level: silent wins outright,
+// otherwise each -v drops the threshold by one slog level step.
+func logLevel(cfg *Config) slog.Level {
+	if cfg.Silent {
+		return slog.LevelError
+	}
+	return slog.LevelInfo - slog.Level(4*cfg.Verbosity)
+}
+
+// CheckResolvers performs DNS checks with requested workers. When benchmark
+// is true, each resolver is probed trials times and the returned statuses
+// carry latency and loss statistics instead of a single Alive bit. When
+// baseline is non-empty, each resolver's answers are also compared against
+// it to flag hijacking. When capabilities is true, DNSSEC/EDNS0/NSID
+// probing is additionally run against each alive resolver.
+func CheckResolvers(resolverChecks []ResolverCheck, workers int, benchmark bool, trials int, baseline string, hijackHosts []string, excludeHijacked, capabilities bool, rt *runner) []ResolverStatus {
 	// Creating channels to manage tasks and results.
 	tasks := make(chan ResolverCheck, len(resolverChecks))
 	wg := sync.WaitGroup{} // WaitGroup to synchronize worker goroutines.
@@ -56,7 +151,7 @@ func CheckResolvers(resolverChecks []ResolverCheck, workers int, silent bool) []
 
 	// Starting worker goroutines.
 	for i := 0; i < workers; i++ {
-		go worker(tasks, &wg, results, silent)
+		go worker(tasks, &wg, results, benchmark, trials, baseline, hijackHosts, excludeHijacked, capabilities, rt)
 	}
 
 	// Feeding tasks to the worker goroutines.
@@ -81,42 +176,486 @@ func CheckResolvers(resolverChecks []ResolverCheck, workers int, silent bool) []
 }
 
 // A worker goroutine that performs DNS checks for the provided tasks.
-func worker(tasks <-chan ResolverCheck, wg *sync.WaitGroup, results chan<- ResolverStatus, silent bool) {
+func worker(tasks <-chan ResolverCheck, wg *sync.WaitGroup, results chan<- ResolverStatus, benchmark bool, trials int, baseline string, hijackHosts []string, excludeHijacked, capabilities bool, rt *runner) {
 	for task := range tasks {
-		// Performing a DNS check.
-		results <- checkResolverStatus(task, silent)
+		var status ResolverStatus
+		if benchmark {
+			status = benchmarkResolverStatus(task, trials, rt)
+		} else {
+			status = checkResolverStatus(task, rt)
+		}
+		if status.Alive && baseline != "" {
+			status.Hijacked = detectHijack(task, baseline, hijackHosts)
+			if status.Hijacked && excludeHijacked {
+				status.Alive = false
+			}
+		}
+		if status.Alive && capabilities {
+			status.Capabilities = probeCapabilities(task)
+		}
+		results <- status
 		wg.Done()
 	}
 }
 
-// CheckResolverStatus performs a DNS check and returns a corresponding DNS resolver status.
-func checkResolverStatus(check ResolverCheck, silent bool) ResolverStatus {
-	isAlive := isAlive(check.Resolver, check.Protocol, check.Timeout)
-	if isAlive && !silent {
-		fmt.Println(check.Resolver)
+// CheckResolverStatus performs a DNS check, retrying with exponential
+// backoff up to rt.retries times, and returns a corresponding DNS resolver
+// status. Unless rt.silent is set, the resolver is also printed to stdout
+// as it's found alive, same as before structured logging was added.
+func checkResolverStatus(check ResolverCheck, rt *runner) ResolverStatus {
+	alive := isAliveWithRetry(check, rt.retries)
+	slog.Info("resolver checked", "resolver", check.Resolver, "protocol", check.Protocol, "alive", alive)
+	protocol := ""
+	if alive {
+		protocol = check.Protocol
+		if !rt.silent {
+			fmt.Printf("%s (%s)\n", check.Resolver, check.Protocol)
+		}
 	}
-	return ResolverStatus{Alive: isAlive, Name: check.Resolver}
+	return ResolverStatus{Alive: alive, Name: check.Resolver, Protocol: protocol}
 }
 
-// IsAlive checks if a DNS resolver is alive by performing a DNS lookup.
-func isAlive(resolverHost, protocol string, timeout time.Duration) bool {
-	r := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			return net.Dial(protocol, resolverHost+":53")
-		},
+// isAliveWithRetry calls isAlive, retrying up to retries times with
+// exponential backoff and jitter when a query times out, so that a single
+// dropped or rate-limited query doesn't read as a dead resolver. Hard
+// failures (connection refused, malformed reply, and the like) are not
+// retried, since backing off won't change their outcome.
+func isAliveWithRetry(check ResolverCheck, retries int) bool {
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err := isAlive(check.Resolver, check.Protocol, check.Path, check.Timeout)
+		if err == nil {
+			return true
+		}
+		if attempt >= retries || !isTimeout(err) {
+			return false
+		}
+		sleep := backoff + time.Duration(rand.Int63n(int64(backoff)+1))
+		slog.Debug("retrying after timeout", "resolver", check.Resolver, "attempt", attempt+1, "backoff", sleep)
+		time.Sleep(sleep)
+		backoff *= 2
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	for _, host := range testHosts {
-		_, err := r.LookupHost(ctx, host)
+}
+
+// benchmarkResolverStatus probes a resolver trials times and summarizes the
+// per-query latencies and error rate into a ResolverStatus. Unless
+// rt.silent is set, a one-line summary is also printed to stdout, same as
+// before structured logging was added.
+func benchmarkResolverStatus(check ResolverCheck, trials int, rt *runner) ResolverStatus {
+	latencies := make([]time.Duration, 0, trials)
+	errors := 0
+
+	for i := 0; i < trials; i++ {
+		start := time.Now()
+		if isAlive(check.Resolver, check.Protocol, check.Path, check.Timeout) == nil {
+			latencies = append(latencies, time.Since(start))
+		} else {
+			errors++
+		}
+	}
+
+	status := ResolverStatus{
+		Name:     check.Resolver,
+		Protocol: check.Protocol,
+		Alive:    len(latencies) > 0,
+		LossPct:  100 * float64(errors) / float64(trials),
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		status.MinMs = msOf(latencies[0])
+		status.MaxMs = msOf(latencies[len(latencies)-1])
+		status.MedianMs = msOf(percentile(latencies, 50))
+		status.P95Ms = msOf(percentile(latencies, 95))
+	}
+
+	slog.Info("resolver benchmarked", "resolver", check.Resolver, "protocol", check.Protocol,
+		"alive", status.Alive, "median_ms", status.MedianMs, "p95_ms", status.P95Ms, "loss_pct", status.LossPct)
+	if status.Alive && !rt.silent {
+		fmt.Printf("%s (%s) median=%.1fms p95=%.1fms loss=%.0f%%\n",
+			check.Resolver, check.Protocol, status.MedianMs, status.P95Ms, status.LossPct)
+	}
+	return status
+}
+
+// detectHijack compares the candidate resolver's answers for each of hosts
+// against the baseline resolver's answers and reports whether the
+// candidate looks like it is hijacking (spoofing, redirecting, or dropping)
+// queries.
+func detectHijack(check ResolverCheck, baseline string, hosts []string) bool {
+	baselineCheck := ResolverCheck{Resolver: baseline, Protocol: "udp", Timeout: check.Timeout}
+	for _, host := range hosts {
+		candidate, err := queryAnswers(check, host)
 		if err != nil {
-			return false
+			continue // the liveness check already accounts for outright failures
+		}
+		trusted, err := queryAnswers(baselineCheck, host)
+		if err != nil {
+			continue
+		}
+		if isHijacked(candidate, trusted) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeCapabilities issues a handful of extra queries to determine whether
+// the resolver validates DNSSEC and what it advertises over EDNS0.
+func probeCapabilities(check ResolverCheck) Capabilities {
+	supportsEDNS0, nsid, maxUDPSize := probeEDNS0(check)
+	return Capabilities{
+		DNSSECValidates: probeDNSSEC(check),
+		SupportsEDNS0:   supportsEDNS0,
+		NSID:            nsid,
+		MaxUDPSize:      maxUDPSize,
+	}
+}
+
+// probeDNSSEC issues a DO=1/CD=0 query against a validly-signed zone
+// (expecting an RRSIG back) and against a zone with broken DNSSEC
+// (expecting SERVFAIL/NXDOMAIN), to determine whether the resolver
+// actually validates signatures rather than just passing the DO bit through.
+func probeDNSSEC(check ResolverCheck) bool {
+	signed := new(dns.Msg)
+	signed.SetQuestion(dns.Fqdn("internetsociety.org"), dns.TypeA)
+	signed.SetEdns0(4096, true)
+	reply, err := sendQuery(check, signed)
+	if err != nil || reply.Rcode != dns.RcodeSuccess {
+		return false
+	}
+	hasRRSIG := false
+	for _, rr := range reply.Answer {
+		if rr.Header().Rrtype == dns.TypeRRSIG {
+			hasRRSIG = true
+			break
+		}
+	}
+	if !hasRRSIG {
+		return false
+	}
+
+	broken := new(dns.Msg)
+	broken.SetQuestion(dns.Fqdn("dnssec-failed.org"), dns.TypeA)
+	broken.SetEdns0(4096, true)
+	reply, err = sendQuery(check, broken)
+	if err != nil {
+		return false
+	}
+	return reply.Rcode == dns.RcodeServerFailure || reply.Rcode == dns.RcodeNameError
+}
+
+// probeEDNS0 sends an id.server CH TXT query carrying an OPT record with
+// an NSID option, and reports whether the resolver answered with its own
+// OPT record, plus any NSID and advertised UDP payload size it returned.
+func probeEDNS0(check ResolverCheck) (supports bool, nsid string, maxUDPSize uint16) {
+	m := new(dns.Msg)
+	m.SetQuestion("id.server.", dns.TypeTXT)
+	m.Question[0].Qclass = dns.ClassCHAOS
+
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	opt.SetUDPSize(4096)
+	opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+	m.Extra = append(m.Extra, opt)
+
+	reply, err := sendQuery(check, m)
+	if err != nil {
+		return false, "", 0
+	}
+
+	for _, rr := range reply.Extra {
+		replyOpt, ok := rr.(*dns.OPT)
+		if !ok {
+			continue
+		}
+		supports = true
+		maxUDPSize = replyOpt.UDPSize()
+		for _, o := range replyOpt.Option {
+			if n, ok := o.(*dns.EDNS0_NSID); ok {
+				nsid = n.Nsid
+			}
+		}
+	}
+	return supports, nsid, maxUDPSize
+}
+
+// isHijacked reports whether a candidate answer set looks spoofed: empty,
+// pointing at a private/loopback address, on the blocklist, or disjoint
+// from the trusted baseline answer set.
+func isHijacked(candidate, trusted []net.IP) bool {
+	if len(candidate) == 0 {
+		return true
+	}
+	for _, ip := range candidate {
+		if isSuspiciousIP(ip) {
+			return true
+		}
+	}
+	if len(trusted) == 0 {
+		return false
+	}
+	for _, c := range candidate {
+		for _, t := range trusted {
+			if c.Equal(t) {
+				return false
+			}
 		}
 	}
 	return true
 }
 
+// blocklistedIPs are well-known sinkhole/poison addresses returned by
+// censoring resolvers in place of a real answer.
+var blocklistedIPs = map[string]bool{
+	"0.0.0.0":         true,
+	"127.0.0.1":       true,
+	"255.255.255.255": true,
+}
+
+// isSuspiciousIP reports whether ip is private, loopback, unspecified, or
+// on the hijack blocklist.
+func isSuspiciousIP(ip net.IP) bool {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	return blocklistedIPs[ip.String()]
+}
+
+// queryAnswers resolves both A and AAAA records for host against the given
+// resolver and returns the combined set of answer IPs.
+func queryAnswers(check ResolverCheck, host string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(host), qtype)
+		reply, err := sendQuery(check, m)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, answerIPs(reply)...)
+	}
+	return ips, nil
+}
+
+// answerIPs extracts A/AAAA record IPs from a DNS message's answer section.
+func answerIPs(m *dns.Msg) []net.IP {
+	var ips []net.IP
+	for _, rr := range m.Answer {
+		switch rec := rr.(type) {
+		case *dns.A:
+			ips = append(ips, rec.A)
+		case *dns.AAAA:
+			ips = append(ips, rec.AAAA)
+		}
+	}
+	return ips
+}
+
+// sendQuery sends m to check's resolver over its configured protocol
+// (udp, tcp, dot, doh, or doq) and returns the parsed reply. Every call
+// passes through the global rate limiter (if --rate was set), so the
+// configured rate bounds the real query volume regardless of how many
+// queries a single liveness check, benchmark trial, or capability probe
+// ends up issuing.
+func sendQuery(check ResolverCheck, m *dns.Msg) (*dns.Msg, error) {
+	if rateLimiter != nil {
+		rateLimiter.Wait(context.Background())
+	}
+	switch check.Protocol {
+	case "dot":
+		return sendDoT(check, m)
+	case "doh":
+		return sendDoH(check, m)
+	case "doq":
+		return sendDoQ(check, m)
+	default:
+		return sendClassic(check, m)
+	}
+}
+
+// sendClassic sends m to check's resolver over plain udp/tcp.
+func sendClassic(check ResolverCheck, m *dns.Msg) (*dns.Msg, error) {
+	c := &dns.Client{Net: check.Protocol, Timeout: check.Timeout}
+	reply, _, err := c.Exchange(m, net.JoinHostPort(check.Resolver, "53"))
+	return reply, err
+}
+
+// percentile returns the pth percentile (0-100) of a sorted latency slice.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	idx := (p * (len(sorted) - 1)) / 100
+	return sorted[idx]
+}
+
+// msOf converts a duration to fractional milliseconds.
+func msOf(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// isAlive checks if a DNS resolver is alive by performing a DNS lookup over
+// the requested protocol: udp, tcp, dot, doh, or doq. It returns the error
+// from the first failing query, or nil if every test host resolved.
+func isAlive(resolverHost, protocol, path string, timeout time.Duration) error {
+	check := ResolverCheck{Resolver: resolverHost, Protocol: protocol, Path: path, Timeout: timeout}
+	for _, host := range testHosts {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(host), dns.TypeA)
+		if _, err := sendQuery(check, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTimeout reports whether err is a timeout or deadline-exceeded failure,
+// as opposed to a hard failure like connection refused or a malformed
+// reply, which retrying won't fix.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// sendDoT sends m to check's resolver over DNS-over-TLS (RFC 7858) on port 853.
+func sendDoT(check ResolverCheck, m *dns.Msg) (*dns.Msg, error) {
+	host, port := splitHostPort(check.Resolver, "853")
+	dialer := &net.Dialer{Timeout: check.Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, port), &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	co := &dns.Conn{Conn: conn}
+	co.SetDeadline(time.Now().Add(check.Timeout))
+	if err := co.WriteMsg(m); err != nil {
+		return nil, err
+	}
+	return co.ReadMsg()
+}
+
+// sendDoH POSTs m to check's resolver over DNS-over-HTTPS (RFC 8484).
+func sendDoH(check ResolverCheck, m *dns.Msg) (*dns.Msg, error) {
+	path := check.Path
+	if path == "" {
+		path = "/dns-query"
+	}
+
+	packed, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://"+check.Resolver+path, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	client := &http.Client{Timeout: check.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("doh: unexpected status %d", resp.StatusCode)
+	}
+
+	reply := new(dns.Msg)
+	if err := reply.Unpack(body); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// sendDoQ sends m to check's resolver over DNS-over-QUIC (RFC 9250) on port 853.
+func sendDoQ(check ResolverCheck, m *dns.Msg) (*dns.Msg, error) {
+	host, port := splitHostPort(check.Resolver, "853")
+	ctx, cancel := context.WithTimeout(context.Background(), check.Timeout)
+	defer cancel()
+
+	tlsConf := &tls.Config{ServerName: host, NextProtos: []string{"doq"}}
+	session, err := quic.DialAddr(ctx, net.JoinHostPort(host, port), tlsConf, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer session.CloseWithError(0, "")
+
+	stream, err := session.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	m.Id = 0 // RFC 9250 requires the message ID to be 0 on the wire.
+	packed, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(packed)))
+	if _, err := stream.Write(append(length, packed...)); err != nil {
+		return nil, err
+	}
+
+	respLength := make([]byte, 2)
+	if _, err := io.ReadFull(stream, respLength); err != nil {
+		return nil, err
+	}
+	respBody := make([]byte, binary.BigEndian.Uint16(respLength))
+	if _, err := io.ReadFull(stream, respBody); err != nil {
+		return nil, err
+	}
+
+	reply := new(dns.Msg)
+	if err := reply.Unpack(respBody); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// splitHostPort splits a "host" or "host:port" string, falling back to
+// defaultPort when no port is present.
+func splitHostPort(hostPort, defaultPort string) (string, string) {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return hostPort, defaultPort
+	}
+	return host, port
+}
+
+// parseResolverEntry parses a resolver list line of the form
+// proto://host[:port][/path], falling back to defaultProtocol when no
+// scheme is present. The path component is only meaningful for DoH.
+func parseResolverEntry(line, defaultProtocol string) (resolver, protocol, path string) {
+	protocol = defaultProtocol
+	rest := line
+	if idx := strings.Index(line, "://"); idx != -1 {
+		protocol = line[:idx]
+		rest = line[idx+len("://"):]
+	}
+
+	if slash := strings.Index(rest, "/"); slash != -1 {
+		path = rest[slash:]
+		rest = rest[:slash]
+	} else if protocol == "doh" {
+		path = "/dns-query"
+	}
+
+	return rest, protocol, path
+}
+
 // LoadResolvers loads DNS resolvers for the provided list and from Stdin if available.
 func LoadResolvers(filename, protocol string, timeout time.Duration) ([]ResolverCheck, error) {
 	// Preparing ResolverCheck objects
@@ -127,7 +666,8 @@ func LoadResolvers(filename, protocol string, timeout time.Duration) ([]Resolver
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
 		scanner := bufio.NewScanner(os.Stdin)
 		for scanner.Scan() {
-			checks = append(checks, ResolverCheck{Resolver: scanner.Text(), Protocol: protocol, Timeout: timeout})
+			resolver, proto, path := parseResolverEntry(scanner.Text(), protocol)
+			checks = append(checks, ResolverCheck{Resolver: resolver, Protocol: proto, Path: path, Timeout: timeout})
 		}
 		if scanner.Err() != nil {
 			return nil, scanner.Err()
@@ -144,7 +684,8 @@ func LoadResolvers(filename, protocol string, timeout time.Duration) ([]Resolver
 
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
-			checks = append(checks, ResolverCheck{Resolver: scanner.Text(), Protocol: protocol, Timeout: timeout})
+			resolver, proto, path := parseResolverEntry(scanner.Text(), protocol)
+			checks = append(checks, ResolverCheck{Resolver: resolver, Protocol: proto, Path: path, Timeout: timeout})
 		}
 		if scanner.Err() != nil {
 			return nil, scanner.Err()
@@ -154,36 +695,183 @@ func LoadResolvers(filename, protocol string, timeout time.Duration) ([]Resolver
 	return checks, nil
 }
 
-// WriteFile writes DNS resolver statuses to a file.
-func writeFile(fileName string, statuses []ResolverStatus) {
+// WriteFile writes DNS resolver statuses to a file in the requested format:
+// txt (alive resolver names, one per line), json, or csv.
+func writeFile(fileName, format string, statuses []ResolverStatus) {
 	file, err := os.Create(fileName)
 	if err != nil {
-		fmt.Println(err)
+		slog.Error(err.Error())
 		os.Exit(1)
 	}
 	defer file.Close()
 
+	switch format {
+	case "json":
+		err = writeJSON(file, statuses)
+	case "csv":
+		err = writeCSV(file, statuses)
+	default:
+		err = writeTxt(file, statuses)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// writeTxt writes the names of the alive resolvers, one per line.
+func writeTxt(w io.Writer, statuses []ResolverStatus) error {
 	for _, resolver := range statuses {
 		if resolver.Alive {
-			_, err = fmt.Fprintln(file, resolver.Name)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+			if _, err := fmt.Fprintln(w, resolver.Name); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
 }
 
-// ParseFlags parses command-line flags.
-func ParseFlags() (string, string, string, int, int64, bool) {
-	list := flag.String("list", "", "List of DNS resolvers")
-	output := flag.String("output", "", "Output file")
-	protocol := flag.String("protocol", "udp", "Network protocol")
-	workers := flag.Int("workers", 10, "Number of workers")
-	timeoutSec := flag.Int64("timeout", 1, "Timeout in seconds")
-	silent := flag.Bool("silent", false, "Silent mode")
+// writeJSON writes the full list of resolver statuses as a JSON array.
+func writeJSON(w io.Writer, statuses []ResolverStatus) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(statuses)
+}
 
-	flag.Parse()
+// writeCSV writes the full list of resolver statuses as CSV with a header row.
+func writeCSV(w io.Writer, statuses []ResolverStatus) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
 
-	return *list, *output, *protocol, *workers, *timeoutSec, *silent
+	header := []string{"resolver", "protocol", "alive", "median_ms", "p95_ms", "loss_pct", "hijacked", "dnssec_validates", "edns0", "nsid"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		row := []string{
+			s.Name,
+			s.Protocol,
+			strconv.FormatBool(s.Alive),
+			strconv.FormatFloat(s.MedianMs, 'f', 2, 64),
+			strconv.FormatFloat(s.P95Ms, 'f', 2, 64),
+			strconv.FormatFloat(s.LossPct, 'f', 2, 64),
+			strconv.FormatBool(s.Hijacked),
+			strconv.FormatBool(s.Capabilities.DNSSECValidates),
+			strconv.FormatBool(s.Capabilities.SupportsEDNS0),
+			s.Capabilities.NSID,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultConfigPath is where ParseFlags looks for an INI config file when
+// --config isn't given.
+const defaultConfigPath = ".resolvix.ini"
+
+// ParseFlags parses command-line flags, then layers in any settings found
+// in an INI config file (--config, or ~/.resolvix.ini if present) for
+// flags that weren't set on the command line explicitly.
+func ParseFlags() *Config {
+	fs := pflag.NewFlagSet("resolvix", pflag.ExitOnError)
+
+	list := fs.StringP("list", "l", "", "List of DNS resolvers")
+	output := fs.StringP("output", "o", "", "Output file")
+	format := fs.String("format", "txt", "Output format: txt, json, or csv")
+	protocol := fs.StringP("protocol", "p", "udp", "Network protocol (udp, tcp, dot, doh, doq)")
+	workers := fs.IntP("workers", "w", 10, "Number of workers")
+	timeout := fs.DurationP("timeout", "t", time.Second, "Per-query timeout, e.g. 500ms or 2s")
+	benchmark := fs.Bool("benchmark", false, "Benchmark mode: measure latency and loss instead of a single liveness check")
+	trials := fs.Int("trials", 5, "Number of queries per resolver in benchmark mode")
+	baseline := fs.String("baseline", "", "Trusted resolver to compare answers against, to detect hijacking")
+	hijackHosts := fs.String("hijack-hosts", "", "Comma-separated extra hosts to check against --baseline, in addition to the built-in test hosts")
+	excludeHijacked := fs.Bool("exclude-hijacked", false, "Exclude hijacked resolvers from the alive output")
+	capabilities := fs.Bool("capabilities", false, "Probe DNSSEC validation and EDNS0/NSID support (triples query volume)")
+	rateLimit := fs.Float64("rate", 0, "Maximum queries per second across all workers (0 = unlimited)")
+	retries := fs.Int("retries", 2, "Retries per resolver on timeout, with exponential backoff and jitter")
+	verbosity := fs.CountP("verbose", "v", "Increase log verbosity (-v, -vv)")
+	silent := fs.BoolP("silent", "s", false, "Silent mode")
+	config := fs.String("config", "", "INI config file (defaults to ~/"+defaultConfigPath+" if present)")
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	path := *config
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, defaultConfigPath)
+		}
+	}
+	if path != "" {
+		if err := applyINIConfig(fs, path); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	hosts := append([]string{}, testHosts...)
+	if *hijackHosts != "" {
+		hosts = append(hosts, strings.Split(*hijackHosts, ",")...)
+	}
+
+	return &Config{
+		List:            *list,
+		Output:          *output,
+		Format:          *format,
+		Protocol:        *protocol,
+		Workers:         *workers,
+		Timeout:         *timeout,
+		Benchmark:       *benchmark,
+		Trials:          *trials,
+		Baseline:        *baseline,
+		HijackHosts:     hosts,
+		ExcludeHijacked: *excludeHijacked,
+		Capabilities:    *capabilities,
+		Rate:            *rateLimit,
+		Retries:         *retries,
+		Verbosity:       *verbosity,
+		Silent:          *silent,
+	}
+}
+
+// applyINIConfig reads a simple "key = value" INI file (comments starting
+// with # or ; and [section] headers are ignored) and applies each entry to
+// fs via Set, skipping any flag that was already set on the command line so
+// explicit flags always win over the config file.
+func applyINIConfig(fs *pflag.FlagSet, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		if fs.Changed(key) {
+			continue
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("config %s: %s: %w", path, key, err)
+		}
+	}
+	return scanner.Err()
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseResolverEntry(t *testing.T) {
+	tests := []struct {
+		name            string
+		line            string
+		defaultProtocol string
+		wantResolver    string
+		wantProtocol    string
+		wantPath        string
+	}{
+		{
+			name:            "bare host falls back to default protocol",
+			line:            "1.1.1.1",
+			defaultProtocol: "udp",
+			wantResolver:    "1.1.1.1",
+			wantProtocol:    "udp",
+		},
+		{
+			name:            "host:port falls back to default protocol",
+			line:            "1.1.1.1:53",
+			defaultProtocol: "tcp",
+			wantResolver:    "1.1.1.1:53",
+			wantProtocol:    "tcp",
+		},
+		{
+			name:            "scheme overrides default protocol",
+			line:            "dot://dns.example.com",
+			defaultProtocol: "udp",
+			wantResolver:    "dns.example.com",
+			wantProtocol:    "dot",
+		},
+		{
+			name:            "doh with explicit path",
+			line:            "doh://dns.example.com/custom-query",
+			defaultProtocol: "udp",
+			wantResolver:    "dns.example.com",
+			wantProtocol:    "doh",
+			wantPath:        "/custom-query",
+		},
+		{
+			name:            "doh without a path defaults to /dns-query",
+			line:            "doh://dns.example.com",
+			defaultProtocol: "udp",
+			wantResolver:    "dns.example.com",
+			wantProtocol:    "doh",
+			wantPath:        "/dns-query",
+		},
+		{
+			name:            "doq with explicit port and no path",
+			line:            "doq://dns.example.com:8853",
+			defaultProtocol: "udp",
+			wantResolver:    "dns.example.com:8853",
+			wantProtocol:    "doq",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, protocol, path := parseResolverEntry(tt.line, tt.defaultProtocol)
+			if resolver != tt.wantResolver || protocol != tt.wantProtocol || path != tt.wantPath {
+				t.Errorf("parseResolverEntry(%q, %q) = (%q, %q, %q), want (%q, %q, %q)",
+					tt.line, tt.defaultProtocol, resolver, protocol, path,
+					tt.wantResolver, tt.wantProtocol, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestIsSuspiciousIP(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want bool
+	}{
+		{"public ip is not suspicious", "8.8.8.8", false},
+		{"private ip is suspicious", "192.168.1.1", true},
+		{"loopback is suspicious", "127.0.0.1", true},
+		{"unspecified is suspicious", "0.0.0.0", true},
+		{"blocklisted sinkhole is suspicious", "255.255.255.255", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSuspiciousIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("isSuspiciousIP(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHijacked(t *testing.T) {
+	tests := []struct {
+		name      string
+		candidate []net.IP
+		trusted   []net.IP
+		want      bool
+	}{
+		{
+			name:      "empty candidate is hijacked",
+			candidate: nil,
+			trusted:   []net.IP{net.ParseIP("93.184.216.34")},
+			want:      true,
+		},
+		{
+			name:      "candidate matching trusted is not hijacked",
+			candidate: []net.IP{net.ParseIP("93.184.216.34")},
+			trusted:   []net.IP{net.ParseIP("93.184.216.34")},
+			want:      false,
+		},
+		{
+			name:      "candidate disjoint from trusted is hijacked",
+			candidate: []net.IP{net.ParseIP("203.0.113.9")},
+			trusted:   []net.IP{net.ParseIP("93.184.216.34")},
+			want:      true,
+		},
+		{
+			name:      "candidate private address is hijacked even without a trusted set",
+			candidate: []net.IP{net.ParseIP("10.0.0.1")},
+			trusted:   nil,
+			want:      true,
+		},
+		{
+			name:      "no trusted answers to compare against is not hijacked",
+			candidate: []net.IP{net.ParseIP("93.184.216.34")},
+			trusted:   nil,
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHijacked(tt.candidate, tt.trusted); got != tt.want {
+				t.Errorf("isHijacked(%v, %v) = %v, want %v", tt.candidate, tt.trusted, got, tt.want)
+			}
+		})
+	}
+}